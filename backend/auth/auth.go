@@ -0,0 +1,215 @@
+// Package auth hanterar JWT-verifiering och rollbaserad åtkomst för moderations-API:t.
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims är de JWT-claims guestbooken bryr sig om, utöver de vanliga registrerade
+type Claims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role"`
+}
+
+type contextKey int
+
+const claimsContextKey contextKey = iota
+
+// Authenticator verifierar JWT:er signerade med antingen HS256 (delad hemlighet)
+// eller RS256 (nycklar hämtade från en JWKS-endpoint)
+type Authenticator struct {
+	secret []byte
+	issuer string
+	jwks   *jwksCache
+}
+
+// New skapar en Authenticator utifrån JWT_SECRET, JWT_ISSUER och JWT_JWKS_URL
+func New(secret, issuer, jwksURL string) *Authenticator {
+	a := &Authenticator{secret: []byte(secret), issuer: issuer}
+	if jwksURL != "" {
+		a.jwks = newJWKSCache(jwksURL)
+	}
+	return a
+}
+
+// Parse verifierar en JWT och returnerar dess claims
+func (a *Authenticator) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, a.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("auth: ogiltig token")
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return nil, fmt.Errorf("auth: oväntad issuer %q", claims.Issuer)
+	}
+	return claims, nil
+}
+
+func (a *Authenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(a.secret) == 0 {
+			return nil, errors.New("auth: JWT_SECRET är inte konfigurerad")
+		}
+		return a.secret, nil
+	case *jwt.SigningMethodRSA:
+		if a.jwks == nil {
+			return nil, errors.New("auth: JWT_JWKS_URL är inte konfigurerad")
+		}
+		kid, _ := token.Header["kid"].(string)
+		return a.jwks.key(kid)
+	default:
+		return nil, fmt.Errorf("auth: ostödd signeringsmetod %v", token.Header["alg"])
+	}
+}
+
+// GenerateToken signerar en HS256-token. Används av test och interna verktyg
+// som behöver utfärda token utan en fullständig auth-server.
+func GenerateToken(secret, issuer, subject, role string, ttl time.Duration) (string, error) {
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    issuer,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+		Role: role,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// WithClaims lägger till claims i en context
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey, claims)
+}
+
+// ClaimsFromContext läser tillbaka claims som Middleware lade in
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*Claims)
+	return claims, ok
+}
+
+// Middleware kräver en giltig Bearer-token och lägger dess claims i requestens context
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		tokenString := strings.TrimPrefix(header, "Bearer ")
+		if tokenString == "" || tokenString == header {
+			http.Error(w, "Saknar giltig Authorization-header", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := a.Parse(tokenString)
+		if err != nil {
+			http.Error(w, "Ogiltig token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+	})
+}
+
+// RequireRole ger en 403 om den inloggade inte har den efterfrågade rollen.
+// Måste köras bakom Middleware så att claims redan finns i contexten.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok || claims.Role != role {
+				http.Error(w, fmt.Sprintf("Kräver rollen %q", role), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// jwksCache håller RSA-publicnycklar hämtade från en JWKS-endpoint, nycklade på kid
+type jwksCache struct {
+	url string
+	ttl time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func newJWKSCache(url string) *jwksCache {
+	return &jwksCache{url: url, ttl: time.Hour}
+}
+
+func (c *jwksCache) key(kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.keys[kid]; ok && time.Since(c.fetchedAt) < c.ttl {
+		return key, nil
+	}
+	if err := c.refresh(); err != nil {
+		return nil, err
+	}
+	key, ok := c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("auth: okänd kid %q i JWKS", kid)
+	}
+	return key, nil
+}
+
+func (c *jwksCache) refresh() error {
+	resp, err := http.Get(c.url)
+	if err != nil {
+		return fmt.Errorf("auth: kunde inte hämta JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("auth: kunde inte läsa JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(body.Keys))
+	for _, k := range body.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		e := 0
+		for _, b := range eBytes {
+			e = e<<8 | int(b)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: e}
+	}
+
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	return nil
+}
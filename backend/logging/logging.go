@@ -0,0 +1,105 @@
+// Package logging ger strukturerad, request-scopad loggning ovanpå log/slog,
+// med ett korrelations-ID (ULID) som följer varje request genom hela kedjan.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oklog/ulid/v2"
+)
+
+// RequestIDHeader är headern klienten kan sätta för att styra korrelations-ID:t,
+// och som middlewaren alltid speglar tillbaka i svaret
+const RequestIDHeader = "X-Request-ID"
+
+type contextKey string
+
+const loggerContextKey contextKey = "logger"
+
+// New skapar en logger som skriver strukturerad JSON till w
+func New(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, nil))
+}
+
+// WithLogger lägger in en logger i kontexten
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// FromContext hämtar loggern ur kontexten, eller slog.Default() om ingen finns
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// recorder fångar statuskod och antal skrivna bytes så att middlewaren kan logga dem
+type recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Flush vidarebefordrar till den underliggande ResponseWriter:n om den stödjer
+// det, så att strömmande handlers (SSE) fungerar genom middlewarekedjan
+func (rec *recorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Middleware genererar eller vidarebefordrar ett korrelations-ID via
+// X-Request-ID, injicerar en request-scopad logger i kontexten, och loggar
+// en rad per avslutad request med metod, path, status, varaktighet, antal
+// bytes och cache-utfall
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if requestID == "" {
+				requestID = ulid.Make().String()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			reqLogger := logger.With("request_id", requestID)
+			r = r.WithContext(WithLogger(r.Context(), reqLogger))
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rec, r)
+
+			path := r.URL.Path
+			if route := mux.CurrentRoute(r); route != nil {
+				if tmpl, err := route.GetPathTemplate(); err == nil {
+					path = tmpl
+				}
+			}
+
+			reqLogger.InfoContext(r.Context(), "request",
+				"method", r.Method,
+				"path", path,
+				"status", rec.status,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"bytes", rec.bytes,
+				"cache", rec.Header().Get("X-Cache"),
+			)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMiddleware_InjectsLoggerAndRoundTripsRequestID testar att korrelations-ID:t
+// dyker upp både i svarsheadern och i loggraderna, och att requestens logger
+// går att nå via kontexten i efterföljande handlers
+func TestMiddleware_InjectsLoggerAndRoundTripsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).InfoContext(r.Context(), "hanterar request")
+		w.Header().Set("X-Cache", "MISS")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hej"))
+	})
+
+	req := httptest.NewRequest("POST", "/api/entries", nil)
+	w := httptest.NewRecorder()
+
+	Middleware(logger)(next).ServeHTTP(w, req)
+
+	requestID := w.Header().Get(RequestIDHeader)
+	assert.NotEmpty(t, requestID)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+
+	var handlerRecord, requestRecord map[string]interface{}
+	assert.NoError(t, json.Unmarshal(lines[0], &handlerRecord))
+	assert.NoError(t, json.Unmarshal(lines[1], &requestRecord))
+
+	assert.Equal(t, requestID, handlerRecord["request_id"])
+	assert.Equal(t, requestID, requestRecord["request_id"])
+	assert.Equal(t, "POST", requestRecord["method"])
+	assert.Equal(t, "/api/entries", requestRecord["path"])
+	assert.Equal(t, float64(http.StatusCreated), requestRecord["status"])
+	assert.Equal(t, "MISS", requestRecord["cache"])
+	assert.Equal(t, float64(3), requestRecord["bytes"])
+}
+
+// TestMiddleware_PropagatesExistingRequestID testar att ett ID satt av klienten
+// (t.ex. en uppströms proxy) återanvänds istället för att ett nytt genereras
+func TestMiddleware_PropagatesExistingRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set(RequestIDHeader, "01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	w := httptest.NewRecorder()
+
+	Middleware(logger)(next).ServeHTTP(w, req)
+
+	assert.Equal(t, "01ARZ3NDEKTSV4RRFFQ69G5FAV", w.Header().Get(RequestIDHeader))
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &record))
+	assert.Equal(t, "01ARZ3NDEKTSV4RRFFQ69G5FAV", record["request_id"])
+}
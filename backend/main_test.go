@@ -5,17 +5,31 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redis/v8"
 	"github.com/go-redis/redismock/v8"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/yaysin/ocp-guestbook/backend/auth"
+	"github.com/yaysin/ocp-guestbook/backend/logging"
+	"github.com/yaysin/ocp-guestbook/backend/metrics"
+	"github.com/yaysin/ocp-guestbook/backend/pubsub"
+	"github.com/yaysin/ocp-guestbook/backend/ratelimit"
 )
 
+const testJWTSecret = "test-secret"
+const testJWTIssuer = "ocp-guestbook-test"
+
 // setupMockApp skapar en App med mockade beroenden
 func setupMockApp(t *testing.T) (*App, sqlmock.Sqlmock, redismock.ClientMock) {
 	// Mock PostgreSQL med MonitorPingsOption aktiverat
@@ -28,9 +42,13 @@ func setupMockApp(t *testing.T) (*App, sqlmock.Sqlmock, redismock.ClientMock) {
 	redisClient, redisMock := redismock.NewClientMock()
 
 	app := &App{
-		DB:    db,
-		Redis: redisClient,
-		Ctx:   context.Background(),
+		DB:      db,
+		Redis:   redisClient,
+		Ctx:     context.Background(),
+		Metrics: metrics.New(),
+		Auth:    auth.New(testJWTSecret, testJWTIssuer, ""),
+		Hub:     pubsub.NewHub(),
+		Logger:  logging.New(io.Discard),
 	}
 
 	return app, mock, redisMock
@@ -141,13 +159,13 @@ func TestGetEntriesHandler_CacheHit(t *testing.T) {
 	defer app.DB.Close()
 
 	// Mockdata
-	cachedEntries := []Entry{
-		{ID: 1, Name: "Test User", Message: "Test message", CreatedAt: time.Now()},
+	envelope := entriesEnvelope{
+		Data: []Entry{{ID: 1, Name: "Test User", Message: "Test message", CreatedAt: time.Now()}},
 	}
-	cachedJSON, _ := json.Marshal(cachedEntries)
+	cachedJSON, _ := json.Marshal(envelope)
 
-	// Setup Redis mock
-	redisMock.ExpectGet("entries:all").SetVal(string(cachedJSON))
+	defaultKey := entriesQuery{Limit: defaultEntriesLimit}.cacheKey()
+	redisMock.ExpectGet(defaultKey).SetVal(string(cachedJSON))
 
 	// Skapa request
 	req := httptest.NewRequest("GET", "/api/entries", nil)
@@ -160,11 +178,11 @@ func TestGetEntriesHandler_CacheHit(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
 
-	var entries []Entry
-	err := json.NewDecoder(w.Body).Decode(&entries)
+	var got entriesEnvelope
+	err := json.NewDecoder(w.Body).Decode(&got)
 	assert.NoError(t, err)
-	assert.Len(t, entries, 1)
-	assert.Equal(t, "Test User", entries[0].Name)
+	assert.Len(t, got.Data, 1)
+	assert.Equal(t, "Test User", got.Data[0].Name)
 }
 
 // TestGetEntriesHandler_CacheMiss testar när cache missar
@@ -174,8 +192,8 @@ func TestGetEntriesHandler_CacheMiss(t *testing.T) {
 
 	now := time.Now()
 
-	// Setup Redis mock (cache miss)
-	redisMock.ExpectGet("entries:all").RedisNil()
+	defaultKey := entriesQuery{Limit: defaultEntriesLimit}.cacheKey()
+	redisMock.ExpectGet(defaultKey).RedisNil()
 
 	// Setup DB mock
 	rows := sqlmock.NewRows([]string{"id", "name", "message", "created_at"}).
@@ -185,8 +203,8 @@ func TestGetEntriesHandler_CacheMiss(t *testing.T) {
 	dbMock.ExpectQuery("SELECT id, name, message, created_at FROM entries").
 		WillReturnRows(rows)
 
-	// Setup Redis Set mock
-	redisMock.ExpectSet("entries:all", sqlmock.AnyArg(), 30*time.Second).SetVal("OK")
+	redisMock.ExpectSet(defaultKey, sqlmock.AnyArg(), 30*time.Second).SetVal("OK")
+	redisMock.ExpectSAdd(entriesListKeysSet, defaultKey).SetVal(1)
 
 	// Skapa request
 	req := httptest.NewRequest("GET", "/api/entries", nil)
@@ -199,16 +217,108 @@ func TestGetEntriesHandler_CacheMiss(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
 
-	var entries []Entry
-	err := json.NewDecoder(w.Body).Decode(&entries)
+	var got entriesEnvelope
+	err := json.NewDecoder(w.Body).Decode(&got)
 	assert.NoError(t, err)
-	assert.Len(t, entries, 2)
-	assert.Equal(t, "Test User", entries[0].Name)
+	assert.Len(t, got.Data, 2)
+	assert.Equal(t, "Test User", got.Data[0].Name)
+	assert.Empty(t, got.NextCursor)
 
 	// Verifiera att alla förväntningar uppfylldes
 	assert.NoError(t, dbMock.ExpectationsWereMet())
 }
 
+// TestGetEntriesHandler_ExcludesHidden testar att dolda inlägg inte listas publikt
+func TestGetEntriesHandler_ExcludesHidden(t *testing.T) {
+	app, dbMock, redisMock := setupMockApp(t)
+	defer app.DB.Close()
+
+	defaultKey := entriesQuery{Limit: defaultEntriesLimit}.cacheKey()
+	redisMock.ExpectGet(defaultKey).RedisNil()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "message", "created_at"}).
+		AddRow(1, "Test User", "Test message", time.Now())
+
+	dbMock.ExpectQuery("SELECT id, name, message, created_at FROM entries WHERE NOT hidden").
+		WillReturnRows(rows)
+
+	redisMock.ExpectSet(defaultKey, sqlmock.AnyArg(), 30*time.Second).SetVal("OK")
+	redisMock.ExpectSAdd(entriesListKeysSet, defaultKey).SetVal(1)
+
+	req := httptest.NewRequest("GET", "/api/entries", nil)
+	w := httptest.NewRecorder()
+
+	app.getEntriesHandler(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+// TestGetEntriesHandler_Pagination testar att next_cursor och Link-headern
+// sätts när det finns fler rader än limit
+func TestGetEntriesHandler_Pagination(t *testing.T) {
+	app, dbMock, redisMock := setupMockApp(t)
+	defer app.DB.Close()
+
+	now := time.Now()
+	eq := entriesQuery{Limit: 1}
+	redisMock.ExpectGet(eq.cacheKey()).RedisNil()
+
+	// Två rader returneras för limit=1 så handlern vet att det finns en till sida
+	rows := sqlmock.NewRows([]string{"id", "name", "message", "created_at"}).
+		AddRow(2, "Newest", "Hej", now).
+		AddRow(1, "Oldest", "Hej igen", now.Add(-time.Minute))
+
+	dbMock.ExpectQuery("SELECT id, name, message, created_at FROM entries").
+		WillReturnRows(rows)
+
+	redisMock.ExpectSet(eq.cacheKey(), sqlmock.AnyArg(), 30*time.Second).SetVal("OK")
+	redisMock.ExpectSAdd(entriesListKeysSet, eq.cacheKey()).SetVal(1)
+
+	req := httptest.NewRequest("GET", "/api/entries?limit=1", nil)
+	w := httptest.NewRecorder()
+
+	app.getEntriesHandler(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var got entriesEnvelope
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Len(t, got.Data, 1)
+	assert.NotEmpty(t, got.NextCursor)
+	assert.Contains(t, w.Header().Get("Link"), `rel="next"`)
+	assert.Contains(t, w.Header().Get("Link"), url.QueryEscape(got.NextCursor))
+
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+// TestGetEntriesHandler_InvalidQuery testar att ogiltiga query-parametrar ger 400
+func TestGetEntriesHandler_InvalidQuery(t *testing.T) {
+	app, _, _ := setupMockApp(t)
+	defer app.DB.Close()
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "Ogiltig limit", query: "?limit=abc"},
+		{name: "Ogiltig cursor", query: "?cursor=!!!"},
+		{name: "Ogiltig since", query: "?since=imorgon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/entries"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			app.getEntriesHandler(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
 // TestCreateEntryHandler_Success testar lyckad skapande
 func TestCreateEntryHandler_Success(t *testing.T) {
 	app, dbMock, redisMock := setupMockApp(t)
@@ -229,7 +339,11 @@ func TestCreateEntryHandler_Success(t *testing.T) {
 		WillReturnRows(rows)
 
 	// Setup Redis mocks
-	redisMock.ExpectDel("entries:all").SetVal(1)
+	defaultKey := entriesQuery{Limit: defaultEntriesLimit}.cacheKey()
+	redisMock.CustomMatch(ignorePublishPayload).ExpectPublish("entries:new", "ignored").SetVal(1)
+	redisMock.ExpectSMembers(entriesListKeysSet).SetVal([]string{defaultKey})
+	redisMock.ExpectDel(defaultKey).SetVal(1)
+	redisMock.ExpectDel(entriesListKeysSet).SetVal(1)
 	redisMock.ExpectIncr("stats:total_entries").SetVal(1)
 
 	// Skapa request
@@ -252,6 +366,7 @@ func TestCreateEntryHandler_Success(t *testing.T) {
 	assert.Equal(t, "Test message", response.Message)
 
 	assert.NoError(t, dbMock.ExpectationsWereMet())
+	assert.NoError(t, redisMock.ExpectationsWereMet())
 }
 
 // TestCreateEntryHandler_InvalidData testar ogiltig data
@@ -342,6 +457,111 @@ func TestStatsHandler(t *testing.T) {
 	assert.NoError(t, dbMock.ExpectationsWereMet())
 }
 
+// TestInvalidateEntriesListCache testar att samtliga spårade varianter av
+// listcachen (ofiltrerad, paginerad, sökning osv) invalideras, inte bara den
+// ofiltrerade förstasidan
+func TestInvalidateEntriesListCache(t *testing.T) {
+	app, _, redisMock := setupMockApp(t)
+	defer app.DB.Close()
+
+	defaultKey := entriesQuery{Limit: defaultEntriesLimit}.cacheKey()
+	searchKey := entriesQuery{Limit: defaultEntriesLimit, Q: "hej"}.cacheKey()
+
+	redisMock.ExpectSMembers(entriesListKeysSet).SetVal([]string{defaultKey, searchKey})
+	redisMock.ExpectDel(defaultKey, searchKey).SetVal(2)
+	redisMock.ExpectDel(entriesListKeysSet).SetVal(1)
+
+	app.invalidateEntriesListCache()
+
+	assert.NoError(t, redisMock.ExpectationsWereMet())
+}
+
+// TestDeleteEntryHandler testar att en moderator kan ta bort ett inlägg
+func TestDeleteEntryHandler(t *testing.T) {
+	app, dbMock, redisMock := setupMockApp(t)
+	defer app.DB.Close()
+
+	defaultKey := entriesQuery{Limit: defaultEntriesLimit}.cacheKey()
+	rows := sqlmock.NewRows([]string{"hidden"}).AddRow(false)
+	dbMock.ExpectQuery("DELETE FROM entries").WithArgs(1).WillReturnRows(rows)
+	redisMock.ExpectSMembers(entriesListKeysSet).SetVal([]string{defaultKey})
+	redisMock.ExpectDel(defaultKey).SetVal(1)
+	redisMock.ExpectDel(entriesListKeysSet).SetVal(1)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/entries/{id}", app.deleteEntryHandler).Methods("DELETE")
+
+	req := httptest.NewRequest("DELETE", "/api/entries/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+	assert.NoError(t, redisMock.ExpectationsWereMet())
+}
+
+// TestDeleteEntryHandler_NotFound testar att borttagning av ett okänt id ger 404
+func TestDeleteEntryHandler_NotFound(t *testing.T) {
+	app, dbMock, _ := setupMockApp(t)
+	defer app.DB.Close()
+
+	dbMock.ExpectQuery("DELETE FROM entries").WithArgs(999).WillReturnError(sql.ErrNoRows)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/entries/{id}", app.deleteEntryHandler).Methods("DELETE")
+
+	req := httptest.NewRequest("DELETE", "/api/entries/999", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+// TestUpdateEntryHandler testar att en moderator kan redigera eller dölja ett inlägg
+func TestUpdateEntryHandler(t *testing.T) {
+	app, dbMock, redisMock := setupMockApp(t)
+	defer app.DB.Close()
+
+	now := time.Now()
+	defaultKey := entriesQuery{Limit: defaultEntriesLimit}.cacheKey()
+
+	dbMock.ExpectQuery("SELECT hidden FROM entries").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"hidden"}).AddRow(false))
+
+	rows := sqlmock.NewRows([]string{"id", "name", "message", "created_at", "hidden"}).
+		AddRow(1, "Test User", "Redigerat meddelande", now, true)
+	dbMock.ExpectQuery("UPDATE entries SET").
+		WithArgs("Redigerat meddelande", true, 1).
+		WillReturnRows(rows)
+	redisMock.ExpectSMembers(entriesListKeysSet).SetVal([]string{defaultKey})
+	redisMock.ExpectDel(defaultKey).SetVal(1)
+	redisMock.ExpectDel(entriesListKeysSet).SetVal(1)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/entries/{id}", app.updateEntryHandler).Methods("PATCH")
+
+	body, _ := json.Marshal(entryPatch{Message: strPtr("Redigerat meddelande"), Hidden: boolPtr(true)})
+	req := httptest.NewRequest("PATCH", "/api/entries/1", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated Entry
+	err := json.NewDecoder(w.Body).Decode(&updated)
+	assert.NoError(t, err)
+	assert.Equal(t, "Redigerat meddelande", updated.Message)
+	assert.True(t, updated.Hidden)
+
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+	assert.NoError(t, redisMock.ExpectationsWereMet())
+}
+
+func strPtr(s string) *string { return &s }
+func boolPtr(b bool) *bool    { return &b }
+
 // TestCORSMiddleware testar CORS middleware
 func TestCORSMiddleware(t *testing.T) {
 	// Skapa en enkel handler
@@ -375,22 +595,131 @@ func TestCORSMiddleware(t *testing.T) {
 	})
 }
 
+// rateLimitIgnoreTimestamp matchar Lua-skriptets argument förutom den sista
+// tidsstämpeln, som varierar mellan körningar
+func rateLimitIgnoreTimestamp(expected, actual []interface{}) error {
+	if len(expected) != len(actual) {
+		return fmt.Errorf("fel antal argument: förväntade %d, fick %d", len(expected), len(actual))
+	}
+	for i := 0; i < len(expected)-1; i++ {
+		if fmt.Sprint(expected[i]) != fmt.Sprint(actual[i]) {
+			return fmt.Errorf("argument %d matchar inte: %v != %v", i, expected[i], actual[i])
+		}
+	}
+	return nil
+}
+
+// ignorePublishPayload matchar kanalnamnet men ignorerar den publicerade
+// payloaden, vars created_at-tidsstämpel varierar mellan körningar
+func ignorePublishPayload(expected, actual []interface{}) error {
+	if len(expected) != len(actual) {
+		return fmt.Errorf("fel antal argument: förväntade %d, fick %d", len(expected), len(actual))
+	}
+	if fmt.Sprint(expected[0]) != fmt.Sprint(actual[0]) {
+		return fmt.Errorf("kommando matchar inte: %v != %v", expected[0], actual[0])
+	}
+	if fmt.Sprint(expected[1]) != fmt.Sprint(actual[1]) {
+		return fmt.Errorf("kanal matchar inte: %v != %v", expected[1], actual[1])
+	}
+	return nil
+}
+
+// TestRateLimitMiddleware testar att middlewaren släpper igenom, avvisar och
+// fail:ar öppet baserat på svaret från Lua-skriptet
+func TestRateLimitMiddleware(t *testing.T) {
+	handlerCalls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalls++
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("Inom gränsen", func(t *testing.T) {
+		app, _, redisMock := setupMockApp(t)
+		defer app.DB.Close()
+		handlerCalls = 0
+
+		redisMock.CustomMatch(rateLimitIgnoreTimestamp).
+			ExpectEval(ratelimit.TokenBucketScript(), []string{"ratelimit:192.0.2.1"}, 10, 20, 0).
+			SetVal([]interface{}{int64(1), int64(19)})
+
+		req := httptest.NewRequest("GET", "/api/entries", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		w := httptest.NewRecorder()
+
+		rateLimitMiddleware(app, 10, 20)(next).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "19", w.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, 1, handlerCalls)
+	})
+
+	t.Run("Hinken tom", func(t *testing.T) {
+		app, _, redisMock := setupMockApp(t)
+		defer app.DB.Close()
+		handlerCalls = 0
+
+		redisMock.CustomMatch(rateLimitIgnoreTimestamp).
+			ExpectEval(ratelimit.TokenBucketScript(), []string{"ratelimit:192.0.2.1"}, 10, 20, 0).
+			SetVal([]interface{}{int64(0), int64(0)})
+
+		req := httptest.NewRequest("GET", "/api/entries", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		w := httptest.NewRecorder()
+
+		rateLimitMiddleware(app, 10, 20)(next).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		assert.Equal(t, 0, handlerCalls)
+	})
+
+	t.Run("Redis nere, släpper igenom", func(t *testing.T) {
+		app, _, redisMock := setupMockApp(t)
+		defer app.DB.Close()
+		handlerCalls = 0
+
+		redisMock.CustomMatch(rateLimitIgnoreTimestamp).
+			ExpectEval(ratelimit.TokenBucketScript(), []string{"ratelimit:192.0.2.1"}, 10, 20, 0).
+			SetErr(redis.ErrClosed)
+
+		req := httptest.NewRequest("GET", "/api/entries", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		w := httptest.NewRecorder()
+
+		rateLimitMiddleware(app, 10, 20)(next).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 1, handlerCalls)
+	})
+}
+
 // TestRouting testar att routes är korrekt konfigurerade
 func TestRouting(t *testing.T) {
-	app, _, _ := setupMockApp(t)
+	app, dbMock, _ := setupMockApp(t)
 	defer app.DB.Close()
 
+	dbMock.ExpectQuery("DELETE FROM entries").WithArgs(999).WillReturnError(sql.ErrNoRows)
+
 	r := mux.NewRouter()
 	r.Use(corsMiddleware)
 	r.HandleFunc("/health", app.healthHandler).Methods("GET")
 	r.HandleFunc("/api/entries", app.getEntriesHandler).Methods("GET")
 	r.HandleFunc("/api/entries", app.createEntryHandler).Methods("POST")
 	r.HandleFunc("/api/stats", app.statsHandler).Methods("GET")
+	r.Handle("/api/entries/{id}", moderatorOnly(app, app.deleteEntryHandler)).Methods("DELETE")
+	r.Handle("/api/entries/{id}", moderatorOnly(app, app.updateEntryHandler)).Methods("PATCH")
+
+	modToken, err := auth.GenerateToken(testJWTSecret, testJWTIssuer, "mod-1", "moderator", time.Hour)
+	assert.NoError(t, err)
+	userToken, err := auth.GenerateToken(testJWTSecret, testJWTIssuer, "user-1", "user", time.Hour)
+	assert.NoError(t, err)
 
 	tests := []struct {
 		name           string
 		method         string
 		path           string
+		authHeader     string
 		expectedStatus int
 	}{
 		{
@@ -411,11 +740,34 @@ func TestRouting(t *testing.T) {
 			path:           "/invalid",
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:           "DELETE utan token",
+			method:         "DELETE",
+			path:           "/api/entries/1",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "DELETE med icke-moderator token",
+			method:         "DELETE",
+			path:           "/api/entries/1",
+			authHeader:     "Bearer " + userToken,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "DELETE med moderator-token når raden inte finns",
+			method:         "DELETE",
+			path:           "/api/entries/999",
+			authHeader:     "Bearer " + modToken,
+			expectedStatus: http.StatusNotFound,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
 			w := httptest.NewRecorder()
 
 			r.ServeHTTP(w, req)
@@ -424,3 +776,104 @@ func TestRouting(t *testing.T) {
 		})
 	}
 }
+
+// TestStreamEntriesHandler testar att nya inlägg skickas till en prenumererande klient
+func TestStreamEntriesHandler(t *testing.T) {
+	app, _, _ := setupMockApp(t)
+	defer app.DB.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/api/entries/stream", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		app.streamEntriesHandler(w, req)
+		close(done)
+	}()
+
+	// Ge handlern tid att hinna prenumerera innan vi publicerar
+	time.Sleep(10 * time.Millisecond)
+	app.Hub.Broadcast([]byte(`{"id":1,"name":"Test User","message":"Hej"}`))
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handlern avslutades inte efter att contexten avbröts")
+	}
+
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `data: {"id":1,"name":"Test User","message":"Hej"}`)
+}
+
+// TestStreamEntriesHandler_ThroughMiddlewareChain testar att strömningen
+// fortfarande fungerar när handlern körs bakom logging- och
+// metricsMiddleware, som båda lindar in ResponseWriter:n
+func TestStreamEntriesHandler_ThroughMiddlewareChain(t *testing.T) {
+	app, _, _ := setupMockApp(t)
+	defer app.DB.Close()
+
+	r := mux.NewRouter()
+	r.Use(logging.Middleware(app.Logger))
+	r.Use(metricsMiddleware(app))
+	r.HandleFunc("/api/entries/stream", app.streamEntriesHandler).Methods("GET")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/api/entries/stream", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		r.ServeHTTP(w, req)
+		close(done)
+	}()
+
+	// Ge handlern tid att hinna prenumerera innan vi publicerar
+	time.Sleep(10 * time.Millisecond)
+	app.Hub.Broadcast([]byte(`{"id":2,"name":"Test User","message":"Hej igen"}`))
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handlern avslutades inte efter att contexten avbröts")
+	}
+
+	assert.NotEqual(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `data: {"id":2,"name":"Test User","message":"Hej igen"}`)
+}
+
+// TestMetricsEndpoint testar att /metrics exponerar Prometheus-mått efter trafik
+func TestMetricsEndpoint(t *testing.T) {
+	app, dbMock, redisMock := setupMockApp(t)
+	defer app.DB.Close()
+
+	dbMock.ExpectPing()
+	redisMock.ExpectPing().SetVal("PONG")
+
+	r := mux.NewRouter()
+	r.Use(corsMiddleware)
+	r.Use(metricsMiddleware(app))
+	r.HandleFunc("/health", app.healthHandler).Methods("GET")
+	r.Handle("/metrics", promhttp.HandlerFor(app.Metrics.Registry, promhttp.HandlerOpts{})).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	metricsReq := httptest.NewRequest("GET", "/metrics", nil)
+	metricsW := httptest.NewRecorder()
+	r.ServeHTTP(metricsW, metricsReq)
+
+	assert.Equal(t, http.StatusOK, metricsW.Code)
+	body := metricsW.Body.String()
+	assert.Contains(t, body, "http_requests_total")
+	assert.Contains(t, body, "http_request_duration_seconds")
+	assert.Contains(t, body, "up{dependency=\"database\"} 1")
+	assert.Contains(t, body, "up{dependency=\"cache\"} 1")
+}
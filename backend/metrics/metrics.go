@@ -0,0 +1,66 @@
+// Package metrics samlar de Prometheus-mått som guestbook-backenden exporterar.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics bär ihop alla mått och den registry de är registrerade mot
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	HTTPRequestsTotal   *prometheus.CounterVec
+	HTTPRequestDuration *prometheus.HistogramVec
+	DBQueryDuration     *prometheus.HistogramVec
+	CacheHitsTotal      prometheus.Counter
+	CacheMissesTotal    prometheus.Counter
+	GuestbookEntries    prometheus.Gauge
+	Up                  *prometheus.GaugeVec
+}
+
+// New skapar en egen registry och registrerar alla mått mot den
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		HTTPRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Totalt antal HTTP-anrop, per path, metod och statuskod",
+		}, []string{"path", "method", "status"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Svarstid för HTTP-anrop, per path och metod",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"path", "method"}),
+		DBQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Svarstid för databasfrågor, per fråga",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"query"}),
+		CacheHitsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "cache_hits_total",
+			Help: "Totalt antal Redis-cacheträffar",
+		}),
+		CacheMissesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "cache_misses_total",
+			Help: "Totalt antal Redis-cachemissar",
+		}),
+		GuestbookEntries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "guestbook_entries",
+			Help: "Nuvarande antal gästboksinlägg",
+		}),
+		Up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "up",
+			Help: "Om ett beroende är nåbart (1) eller inte (0)",
+		}, []string{"dependency"}),
+	}
+
+	m.Registry.MustRegister(
+		m.HTTPRequestsTotal,
+		m.HTTPRequestDuration,
+		m.DBQueryDuration,
+		m.CacheHitsTotal,
+		m.CacheMissesTotal,
+		m.GuestbookEntries,
+		m.Up,
+	)
+
+	return m
+}
@@ -0,0 +1,88 @@
+// Package pubsub fördelar meddelanden från en Redis pub/sub-kanal till flera
+// lokala lyssnare, t.ex. SSE-anslutningar i samma process.
+package pubsub
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const subscriberBuffer = 16
+
+// Hub fördelar inkommande meddelanden till alla aktiva prenumeranter
+type Hub struct {
+	subscribers map[chan []byte]struct{}
+	subscribe   chan chan []byte
+	unsubscribe chan chan []byte
+	publish     chan []byte
+}
+
+// NewHub skapar en tom Hub. Starta Run i en egen goroutine för att börja
+// vidarebefordra meddelanden från Redis.
+func NewHub() *Hub {
+	h := &Hub{
+		subscribers: make(map[chan []byte]struct{}),
+		subscribe:   make(chan chan []byte),
+		unsubscribe: make(chan chan []byte),
+		publish:     make(chan []byte),
+	}
+	go h.loop()
+	return h
+}
+
+func (h *Hub) loop() {
+	for {
+		select {
+		case ch := <-h.subscribe:
+			h.subscribers[ch] = struct{}{}
+		case ch := <-h.unsubscribe:
+			if _, ok := h.subscribers[ch]; ok {
+				delete(h.subscribers, ch)
+				close(ch)
+			}
+		case payload := <-h.publish:
+			for ch := range h.subscribers {
+				select {
+				case ch <- payload:
+				default:
+					// Prenumeranten hänger inte med; hoppa över uppdateringen
+					// hellre än att blockera hela hubben.
+				}
+			}
+		}
+	}
+}
+
+// Subscribe registrerar en ny lyssnare. Anropa den returnerade funktionen för
+// att avregistrera lyssnaren och stänga kanalen.
+func (h *Hub) Subscribe() (<-chan []byte, func()) {
+	ch := make(chan []byte, subscriberBuffer)
+	h.subscribe <- ch
+	return ch, func() { h.unsubscribe <- ch }
+}
+
+// Broadcast skickar payload till alla aktiva prenumeranter
+func (h *Hub) Broadcast(payload []byte) {
+	h.publish <- payload
+}
+
+// Run prenumererar på en Redis pub/sub-kanal och vidarebefordrar varje
+// meddelande till Broadcast tills ctx avbryts.
+func (h *Hub) Run(ctx context.Context, rdb *redis.Client, channel string) error {
+	sub := rdb.Subscribe(ctx, channel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			h.Broadcast([]byte(msg.Payload))
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub_SubscribeAndBroadcast(t *testing.T) {
+	h := NewHub()
+
+	ch, unsubscribe := h.Subscribe()
+	defer unsubscribe()
+
+	h.Broadcast([]byte(`{"id":1}`))
+
+	select {
+	case msg := <-ch:
+		assert.Equal(t, `{"id":1}`, string(msg))
+	case <-time.After(time.Second):
+		t.Fatal("fick inget meddelande inom tidsgränsen")
+	}
+}
+
+func TestHub_UnsubscribeStopsDelivery(t *testing.T) {
+	h := NewHub()
+
+	ch, unsubscribe := h.Subscribe()
+	unsubscribe()
+
+	h.Broadcast([]byte("hello"))
+
+	_, open := <-ch
+	assert.False(t, open, "kanalen ska vara stängd efter unsubscribe")
+}
+
+func TestHub_MultipleSubscribersAllReceive(t *testing.T) {
+	h := NewHub()
+
+	ch1, unsubscribe1 := h.Subscribe()
+	defer unsubscribe1()
+	ch2, unsubscribe2 := h.Subscribe()
+	defer unsubscribe2()
+
+	h.Broadcast([]byte("nytt inlägg"))
+
+	for _, ch := range []<-chan []byte{ch1, ch2} {
+		select {
+		case msg := <-ch:
+			assert.Equal(t, "nytt inlägg", string(msg))
+		case <-time.After(time.Second):
+			t.Fatal("fick inget meddelande inom tidsgränsen")
+		}
+	}
+}
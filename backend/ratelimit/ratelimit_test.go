@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// ignoreTimestampArg matchar alla argument förutom det sista ("now"), som
+// varierar mellan körningar
+func ignoreTimestampArg(expected, actual []interface{}) error {
+	if len(expected) != len(actual) {
+		return fmt.Errorf("fel antal argument: förväntade %d, fick %d", len(expected), len(actual))
+	}
+	for i := 0; i < len(expected)-1; i++ {
+		if fmt.Sprint(expected[i]) != fmt.Sprint(actual[i]) {
+			return fmt.Errorf("argument %d matchar inte: %v != %v", i, expected[i], actual[i])
+		}
+	}
+	return nil
+}
+
+// TestLimiter_Allow testar att svaret från Lua-skriptet tolkas korrekt
+func TestLimiter_Allow(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    []interface{}
+		wantAllow bool
+		wantLeft  int
+	}{
+		{
+			name:      "Tokens kvar",
+			script:    []interface{}{int64(1), int64(4)},
+			wantAllow: true,
+			wantLeft:  4,
+		},
+		{
+			name:      "Hinken tom",
+			script:    []interface{}{int64(0), int64(0)},
+			wantAllow: false,
+			wantLeft:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, mock := redismock.NewClientMock()
+			mock.CustomMatch(ignoreTimestampArg).
+				ExpectEval(tokenBucketScript, []string{"ratelimit:127.0.0.1"}, 5, 10, 0).
+				SetVal(tt.script)
+
+			limiter := New(rdb, 5, 10)
+			result, err := limiter.Allow(context.Background(), "127.0.0.1")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantAllow, result.Allowed)
+			assert.Equal(t, tt.wantLeft, result.Remaining)
+		})
+	}
+}
+
+// TestClientIP testar att X-Forwarded-For föredras framför RemoteAddr
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		expected   string
+	}{
+		{
+			name:       "Utan proxy",
+			remoteAddr: "10.0.0.5:54321",
+			expected:   "10.0.0.5",
+		},
+		{
+			name:       "Bakom OpenShift-route",
+			remoteAddr: "10.0.0.5:54321",
+			forwarded:  "203.0.113.9, 10.0.0.1",
+			expected:   "203.0.113.9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			assert.Equal(t, tt.expected, ClientIP(req))
+		})
+	}
+}